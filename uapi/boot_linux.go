@@ -0,0 +1,87 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+//go:build linux
+
+package uapi
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// KexecLoad loads the entry's kernel and, if present, initrd into the
+// currently running kernel via kexec_file_load(2), ready to be activated by
+// Boot. The entry is validated first, so callers do not need to call
+// Validate themselves.
+func (e *Entry) KexecLoad() error {
+	if err := e.Validate(); err != nil {
+		return fmt.Errorf("kexec load: %w", err)
+	}
+
+	kernelFd, err := memfd("kernel", e.Linux)
+	if err != nil {
+		return fmt.Errorf("kexec load: %w", err)
+	}
+	defer unix.Close(kernelFd)
+
+	initrdFd, flags := -1, 0
+
+	if len(e.Initrd) > 0 {
+		if initrdFd, err = memfd("initrd", e.Initrd); err != nil {
+			return fmt.Errorf("kexec load: %w", err)
+		}
+		defer unix.Close(initrdFd)
+	} else {
+		flags |= unix.KEXEC_FILE_NO_INITRAMFS
+	}
+
+	if err := unix.KexecFileLoad(kernelFd, initrdFd, e.Options, flags); err != nil {
+		return fmt.Errorf("kexec load: %w", err)
+	}
+
+	return nil
+}
+
+// Boot loads the entry via KexecLoad and immediately switches execution to
+// it, replacing the running kernel. On success it does not return.
+func (e *Entry) Boot() error {
+	if err := e.KexecLoad(); err != nil {
+		return err
+	}
+
+	if err := unix.Reboot(unix.LINUX_REBOOT_CMD_KEXEC); err != nil {
+		return fmt.Errorf("boot: %w", err)
+	}
+
+	return nil
+}
+
+// memfd creates an anonymous, memory-backed file containing data and
+// returns its file descriptor, positioned back at offset 0.
+func memfd(name string, data []byte) (int, error) {
+	fd, err := unix.MemfdCreate(name, 0)
+	if err != nil {
+		return -1, fmt.Errorf("memfd_create %s: %w", name, err)
+	}
+
+	for written := 0; written < len(data); {
+		n, err := unix.Write(fd, data[written:])
+		if err != nil {
+			unix.Close(fd)
+			return -1, fmt.Errorf("writing %s: %w", name, err)
+		}
+
+		written += n
+	}
+
+	if _, err := unix.Seek(fd, 0, 0); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("seeking %s: %w", name, err)
+	}
+
+	return fd, nil
+}
@@ -0,0 +1,22 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+//go:build !linux
+
+package uapi
+
+import "errors"
+
+// KexecLoad is unsupported outside Linux, which is the only platform
+// implementing kexec_file_load(2).
+func (e *Entry) KexecLoad() error {
+	return errors.ErrUnsupported
+}
+
+// Boot is unsupported outside Linux, which is the only platform
+// implementing kexec_file_load(2).
+func (e *Entry) Boot() error {
+	return errors.ErrUnsupported
+}
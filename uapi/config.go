@@ -0,0 +1,113 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Config represents the contents loaded from systemd-boot's loader.conf.
+type Config struct {
+	// Default is the config file name (without extension) of the default
+	// entry, optionally containing glob wildcards ('*' and '?').
+	Default string
+	// Timeout is the menu timeout, in seconds, or one of "menu-force",
+	// "menu-hidden" or "no".
+	Timeout string
+	// ConsoleMode is the console mode to use for the menu.
+	ConsoleMode string
+	// Editor enables or disables the kernel command line editor.
+	Editor bool
+	// AutoEntries enables or disables automatic discovery of entries that
+	// are not explicitly listed.
+	AutoEntries bool
+}
+
+// parseBool parses the loader.conf boolean values "yes"/"no" and "1"/"0",
+// defaulting to true as systemd-boot does for unrecognized values.
+func parseBool(v string) bool {
+	switch v {
+	case "no", "0", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+// LoadConfig parses systemd-boot's loader.conf at path from fsys. Unknown
+// keys are ignored, as loader.conf is expected to evolve over time.
+func LoadConfig(fsys fs.FS, path string) (*Config, error) {
+	cfg := &Config{
+		Editor:      true,
+		AutoEntries: true,
+	}
+
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading loader config, %v", err)
+	}
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, " ", 2)
+		if len(kv) < 2 {
+			continue
+		}
+
+		k := kv[0]
+		v := strings.TrimSpace(kv[1])
+
+		switch k {
+		case "default":
+			cfg.Default = v
+		case "timeout":
+			cfg.Timeout = v
+		case "console-mode":
+			cfg.ConsoleMode = v
+		case "editor":
+			cfg.Editor = parseBool(v)
+		case "auto-entries":
+			cfg.AutoEntries = parseBool(v)
+		}
+	}
+
+	return cfg, nil
+}
+
+// SelectDefault returns the default entry out of entries, which is expected
+// to already be sorted as returned by LoadEntries. cfg.Default, if set, is
+// matched as a glob pattern against each entry's config file name (without
+// the ".conf" extension). If cfg is nil, cfg.Default does not match any
+// entry, or cfg.Default is empty, the first entry is returned instead, as
+// systemd-boot does.
+func SelectDefault(entries []*Entry, cfg *Config) (*Entry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries to select a default from")
+	}
+
+	if cfg != nil && cfg.Default != "" {
+		for _, e := range entries {
+			ok, err := path.Match(cfg.Default, e.file)
+			if err != nil {
+				return nil, fmt.Errorf("error matching default entry pattern, %v", err)
+			}
+
+			if ok {
+				return e, nil
+			}
+		}
+	}
+
+	return entries[0], nil
+}
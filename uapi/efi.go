@@ -0,0 +1,121 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"bytes"
+	"debug/pe"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ukiSections maps the PE sections embedded in a Type #2 Unified Kernel
+// Image to the fields they populate on Entry.
+var ukiSections = []string{".osrel", ".cmdline", ".linux", ".initrd"}
+
+// LoadEFIEntries enumerates the Type #2 Unified Kernel Images found under
+// "EFI/Linux/*.efi" and returns an Entry per image, populated from the PE
+// sections embedded in it. The returned entries are sorted the same way as
+// LoadEntries, so the two can be merged and sorted together.
+func LoadEFIEntries(fsys fs.FS, dir string) ([]*Entry, error) {
+	matches, err := fs.Glob(fsys, path.Join(dir, "EFI", "Linux", "*.efi"))
+	if err != nil {
+		return nil, fmt.Errorf("error globbing EFI entries directory, %v", err)
+	}
+
+	entries := make([]*Entry, 0, len(matches))
+
+	for _, m := range matches {
+		e, err := loadUKIEntry(fsys, m)
+		if err != nil {
+			return nil, fmt.Errorf("error loading UKI entry %s, %v", m, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return lessEntry(entries[i], entries[j])
+	})
+
+	return entries, nil
+}
+
+// loadUKIEntry reads the unified kernel image at p and parses its .osrel,
+// .cmdline, .linux and .initrd PE sections into an Entry.
+func loadUKIEntry(fsys fs.FS, p string) (*Entry, error) {
+	data, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("error reading UKI file, %v", err)
+	}
+
+	// debug/pe needs an io.ReaderAt to read section data on demand; the
+	// file has already been loaded whole, so bytes.Reader serves as the
+	// ReaderAt shim.
+	pf, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing PE file, %v", err)
+	}
+	defer pf.Close()
+
+	e := &Entry{
+		fsys: fsys,
+		file: strings.TrimSuffix(path.Base(p), ".efi"),
+	}
+
+	for _, name := range ukiSections {
+		section := pf.Section(name)
+		if section == nil {
+			continue
+		}
+
+		sectionData, err := section.Data()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s section, %v", name, err)
+		}
+
+		switch name {
+		case ".osrel":
+			osrel := parseOSRelease(sectionData)
+			e.Title = osrel["PRETTY_NAME"]
+			e.Version = osrel["VERSION_ID"]
+		case ".cmdline":
+			e.Options = strings.TrimSpace(string(sectionData))
+		case ".linux":
+			e.Linux = sectionData
+		case ".initrd":
+			e.Initrd = sectionData
+		}
+	}
+
+	return e, nil
+}
+
+// parseOSRelease parses the key=value, shell-style format used by
+// os-release(5), as embedded in a UKI's .osrel section.
+func parseOSRelease(data []byte) map[string]string {
+	values := make(map[string]string)
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) < 2 {
+			continue
+		}
+
+		values[kv[0]] = strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+	}
+
+	return values
+}
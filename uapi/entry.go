@@ -10,32 +10,54 @@
 package uapi
 
 import (
-	"bufio"
 	"fmt"
 	"io/fs"
-	"regexp"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Entry represents the contents loaded from a Type #1 Boot Loader Entry.
 type Entry struct {
 	// Title is the human-readable entry title.
 	Title string
+	// Version is the entry version, used for sorting.
+	Version string
+	// MachineID is the entry machine-id, used for sorting.
+	MachineID string
+	// SortKey is the entry sort-key, used for sorting.
+	SortKey string
+	// Architecture restricts the entry to a single EFI architecture.
+	Architecture string
 	// Linux is the kernel image to execute.
 	Linux []byte
 	// Initrd is the ramdisk cpio image, multiple entries are concatenated.
 	Initrd []byte
 	// Options is the kernel parameters.
 	Options string
+	// EFI is a standalone EFI program to execute, as an alternative to Linux.
+	EFI []byte
+	// Devicetree is the devicetree binary blob to pass to the kernel.
+	Devicetree []byte
+	// DevicetreeOverlay is the devicetree overlay, multiple entries are concatenated.
+	DevicetreeOverlay []byte
 
 	parsed  string
 	ignored string
 
+	// file is the base name, without extension, of the file the entry was
+	// loaded from, or the title for GRUB menu entries, which have no file of
+	// their own. It is used for sorting and for default entry matching.
+	file string
+
 	fsys fs.FS
 }
 
 func (e *Entry) loadKeyValue(v string) ([]byte, error) {
-	v = strings.ReplaceAll(v, `/`, `\`)
+	v = strings.TrimPrefix(v, "/")
 	return fs.ReadFile(e.fsys, v)
 }
 
@@ -53,6 +75,14 @@ func (e *Entry) parseKey(line string) (err error) {
 	switch k {
 	case "title":
 		e.Title = v
+	case "version":
+		e.Version = v
+	case "machine-id":
+		e.MachineID = v
+	case "sort-key":
+		e.SortKey = v
+	case "architecture":
+		e.Architecture = v
 	case "linux":
 		if e.Linux, err = e.loadKeyValue(v); err != nil {
 			return
@@ -67,6 +97,22 @@ func (e *Entry) parseKey(line string) (err error) {
 		e.Initrd = append(e.Initrd, initrd...)
 	case "options":
 		e.Options += v
+	case "efi":
+		if e.EFI, err = e.loadKeyValue(v); err != nil {
+			return
+		}
+	case "devicetree":
+		if e.Devicetree, err = e.loadKeyValue(v); err != nil {
+			return
+		}
+	case "devicetree-overlay":
+		var overlay []byte
+
+		if overlay, err = e.loadKeyValue(v); err != nil {
+			return
+		}
+
+		e.DevicetreeOverlay = append(e.DevicetreeOverlay, overlay...)
 	default:
 		e.ignored += line
 		return
@@ -89,12 +135,13 @@ func (e *Entry) Ignored() string {
 
 // LoadEntry parses Type #1 Boot Loader Specification Entries from the argument
 // file and loads each key contents from the argument file system.
-func LoadEntry(fsys fs.FS, path string) (e *Entry, err error) {
+func LoadEntry(fsys fs.FS, p string) (e *Entry, err error) {
 	e = &Entry{
 		fsys: fsys,
+		file: strings.TrimSuffix(path.Base(p), ".conf"),
 	}
 
-	entry, err := fs.ReadFile(fsys, path)
+	entry, err := fs.ReadFile(fsys, p)
 
 	if err != nil {
 		return nil, fmt.Errorf("error reading entry file, %v", err)
@@ -109,126 +156,148 @@ func LoadEntry(fsys fs.FS, path string) (e *Entry, err error) {
 	return
 }
 
-func ExtractGrubMenuentries(data string) ([]string, error) {
-	var entries []string
-	lines := strings.Split(data, "\n")
-
-	var collecting bool
-	var braceLevel int
-	var current []string
-
-	menuentryStart := regexp.MustCompile(`^\s*menuentry\s+'([^']+)'`)
-
-	for _, line := range lines {
-		if !collecting {
-			if menuentryStart.MatchString(line) {
-				collecting = true
-				braceLevel = 0
-				current = []string{line}
-
-				if strings.Contains(line, "{") {
-					braceLevel++
-					if strings.Count(line, "}") > 0 {
-						braceLevel -= strings.Count(line, "}")
-					}
-				}
-			}
-			continue
+// entriesDirs are the locations searched, in order, for Type #1 Boot Loader
+// Entries, relative to the ESP or XBOOTLDR partition root.
+var entriesDirs = []string{
+	"loader/entries",
+	"boot/loader/entries",
+}
+
+// goarchToBLS maps runtime.GOARCH to the architecture identifiers defined by
+// the Boot Loader Specification.
+var goarchToBLS = map[string]string{
+	"386":     "ia32",
+	"amd64":   "x64",
+	"arm":     "arm",
+	"arm64":   "aa64",
+	"riscv64": "riscv64",
+}
+
+// matchesArchitecture reports whether an entry with the given BLS
+// architecture identifier should be considered on the running system.
+func matchesArchitecture(arch string) bool {
+	if arch == "" {
+		return true
+	}
+
+	return arch == goarchToBLS[runtime.GOARCH]
+}
+
+// LoadEntries searches the Type #1 Boot Loader Entries directories in order
+// and parses every "*.conf" file found in the first one holding any, loading
+// each key's contents from fsys; later directories are not read. Entries
+// whose architecture does not match runtime.GOARCH are skipped. The returned
+// slice is sorted per the Boot Loader Specification: descending by sort-key,
+// then by machine-id, then descending by version, then by filename.
+func LoadEntries(fsys fs.FS, dir string) ([]*Entry, error) {
+	var (
+		matches []string
+		err     error
+	)
+
+	for _, d := range entriesDirs {
+		matches, err = fs.Glob(fsys, path.Join(dir, d, "*.conf"))
+		if err != nil {
+			return nil, fmt.Errorf("error globbing entries directory, %v", err)
 		}
 
-		current = append(current, line)
+		if len(matches) > 0 {
+			break
+		}
+	}
 
-		braceLevel += strings.Count(line, "{")
-		braceLevel -= strings.Count(line, "}")
+	entries := make([]*Entry, 0, len(matches))
 
-		if braceLevel == 0 {
-			entries = append(entries, strings.Join(current, "\n"))
-			collecting = false
-			current = nil
+	for _, m := range matches {
+		e, err := LoadEntry(fsys, m)
+		if err != nil {
+			return nil, fmt.Errorf("error loading entry %s, %v", m, err)
 		}
+
+		if !matchesArchitecture(e.Architecture) {
+			continue
+		}
+
+		entries = append(entries, e)
 	}
 
+	sort.SliceStable(entries, func(i, j int) bool {
+		return lessEntry(entries[i], entries[j])
+	})
+
 	return entries, nil
 }
 
-func LoadGrubEntry(fsys fs.FS, path string) (e *Entry, err error) {
-	e = &Entry{
-		fsys: fsys,
+// lessEntry reports whether a sorts before b following the Boot Loader
+// Specification ordering.
+func lessEntry(a, b *Entry) bool {
+	if a.SortKey != b.SortKey {
+		return a.SortKey > b.SortKey
 	}
 
-	toParse, err := fs.ReadFile(fsys, path)
-	blocks, _ := ExtractGrubMenuentries(string(toParse))
-	// There could be many more entries, but in similar
-	// fashion to how it is done with standard UAPI boot
-	// entry, lets take just the first entry
-	menuentryBlock := blocks[0]
-
-	titleRe := regexp.MustCompile(`menuentry\s+'([^']+)'`)
-	m := titleRe.FindStringSubmatch(menuentryBlock)
-	if m == nil {
-		return nil, fmt.Errorf("could not parse menuentry title")
+	if a.MachineID != b.MachineID {
+		return a.MachineID < b.MachineID
 	}
-	e.Title = m[1]
 
-	bodyRe := regexp.MustCompile(`\{([^}]*)\}`)
-	m = bodyRe.FindStringSubmatch(menuentryBlock)
-	if m == nil {
-		return nil, fmt.Errorf("menuentry block missing braces")
+	if a.Version != b.Version {
+		return compareVersions(a.Version, b.Version) > 0
 	}
-	body := m[1]
 
-	sc := bufio.NewScanner(strings.NewReader(body))
+	return a.file < b.file
+}
 
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+// compareVersions compares two version strings by splitting them into runs
+// of digits and non-digits, comparing digit runs numerically and other runs
+// lexicographically, so that e.g. "6.1.10" sorts above "6.1.9". It returns a
+// negative number, zero, or a positive number as a < b, a == b, or a > b.
+func compareVersions(a, b string) int {
+	ar, br := splitVersionRuns(a), splitVersionRuns(b)
 
-		fields := strings.Fields(line)
-		if len(fields) == 0 {
-			continue
+	for i := 0; i < len(ar) && i < len(br); i++ {
+		if c := compareVersionRun(ar[i], br[i]); c != 0 {
+			return c
 		}
+	}
 
-		switch fields[0] {
-		case "linux":
-			if len(fields) < 2 {
-				continue
-			}
-			kernel := fields[1]
-
-			data, err := e.loadKeyValue(kernel)
-			if err != nil {
-				return nil, fmt.Errorf("loading linux image %s: %w", kernel, err)
-			}
-			e.Linux = data
-
-			if len(fields) > 2 {
-				e.Options = strings.Join(fields[2:], " ")
-			}
-
-		case "initrd":
-			if len(fields) < 2 {
-				continue
-			}
-			for _, p := range fields[1:] {
-				initrd, err := e.loadKeyValue(p)
-				if err != nil {
-					return nil, fmt.Errorf("loading initrd %s: %w", p, err)
-				}
-				e.Initrd = append(e.Initrd, initrd...)
-			}
+	return len(ar) - len(br)
+}
 
-		default:
-			e.ignored += line + "\n"
+// splitVersionRuns splits s into consecutive runs of digits and non-digits.
+func splitVersionRuns(s string) []string {
+	var runs []string
+
+	isDigit := func(r rune) bool { return unicode.IsDigit(r) }
+
+	for len(s) > 0 {
+		var i int
+		for i < len(s) && isDigit(rune(s[i])) == isDigit(rune(s[0])) {
+			i++
 		}
 
-		e.parsed += line + "\n"
+		runs = append(runs, s[:i])
+		s = s[i:]
 	}
 
-	if err := sc.Err(); err != nil {
-		return nil, err
+	return runs
+}
+
+// compareVersionRun compares two runs produced by splitVersionRuns, treating
+// them numerically if both are made of digits, and lexicographically
+// otherwise.
+func compareVersionRun(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+
+	if aErr == nil && bErr == nil {
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		default:
+			return 0
+		}
 	}
 
-	return
+	return strings.Compare(a, b)
 }
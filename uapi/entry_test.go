@@ -0,0 +1,124 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"6.1.10", "6.1.9", 1},
+		{"6.1.9", "6.1.10", -1},
+		{"6.1.10", "6.1.10", 0},
+		{"6.1.10-rc2", "6.1.10-rc10", -1},
+		{"2", "10", -1},
+		{"10", "2", 1},
+	}
+
+	for _, c := range cases {
+		if got := sign(compareVersions(c.a, c.b)); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want sign %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func TestMatchesArchitecture(t *testing.T) {
+	if !matchesArchitecture("") {
+		t.Error("empty architecture should always match")
+	}
+
+	if got := goarchToBLS["arm64"]; got != "aa64" {
+		t.Errorf("goarchToBLS[arm64] = %q, want aa64", got)
+	}
+
+	if matchesArchitecture("definitely-not-a-real-arch") {
+		t.Error("bogus architecture should not match")
+	}
+}
+
+func TestLoadEntriesOrdering(t *testing.T) {
+	fsys := fstest.MapFS{
+		"loader/entries/a.conf": &fstest.MapFile{Data: []byte(
+			"title A\nsort-key zzz\nversion 1\n",
+		)},
+		"loader/entries/b.conf": &fstest.MapFile{Data: []byte(
+			"title B\nsort-key zzz\nversion 2\n",
+		)},
+		"loader/entries/c.conf": &fstest.MapFile{Data: []byte(
+			"title C\nsort-key aaa\nversion 99\n",
+		)},
+		"loader/entries/d.conf": &fstest.MapFile{Data: []byte(
+			"title D\n",
+		)},
+	}
+
+	entries, err := LoadEntries(fsys, ".")
+	if err != nil {
+		t.Fatalf("LoadEntries: %v", err)
+	}
+
+	var titles []string
+	for _, e := range entries {
+		titles = append(titles, e.Title)
+	}
+
+	// "zzz" sort-key sorts before "aaa" (descending), entries without a
+	// sort-key sort last; within the same sort-key, higher version first.
+	want := []string{"B", "A", "C", "D"}
+
+	for i, title := range titles {
+		if title != want[i] {
+			t.Fatalf("entries order = %v, want %v", titles, want)
+		}
+	}
+}
+
+func TestLoadEntriesFallbackDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"boot/loader/entries/a.conf": &fstest.MapFile{Data: []byte("title A\n")},
+	}
+
+	entries, err := LoadEntries(fsys, ".")
+	if err != nil {
+		t.Fatalf("LoadEntries: %v", err)
+	}
+
+	if len(entries) != 1 || entries[0].Title != "A" {
+		t.Fatalf("entries = %v, want a single entry titled A", entries)
+	}
+}
+
+func TestLessEntryFilenameTiebreak(t *testing.T) {
+	entries := []*Entry{
+		{file: "b"},
+		{file: "a"},
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return lessEntry(entries[i], entries[j])
+	})
+
+	if entries[0].file != "a" || entries[1].file != "b" {
+		t.Fatalf("entries sorted by filename = %v", entries)
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// GrubConfig holds the top-level settings read from grub.cfg, set through
+// "set default=..." and "set timeout=..." statements outside of any
+// menuentry or submenu block.
+type GrubConfig struct {
+	// Default is the index, or title, of the default menu entry.
+	Default string
+	// Timeout is the menu timeout, in seconds.
+	Timeout string
+}
+
+// grubStatement is a single top-level statement found while scanning a
+// grub.cfg block: either a menuentry/submenu block, in which case words
+// holds its header ("menuentry", title, ...) and body holds the unparsed
+// text between its matching braces, or a plain command line, in which case
+// words holds the line's tokens and body is empty.
+type grubStatement struct {
+	words []string
+	body  string
+}
+
+// LoadGrubEntries parses every menuentry found in the GRUB configuration
+// file at path, including those nested inside submenu blocks, which are
+// flattened into the result with a "Parent > Child" title. Unlike
+// LoadEntries, GRUB has no notion of a file per entry. The linux, initrd and
+// devicetree images are read from fsys as is: "set root=" and "search"
+// commands are tracked but do not change where the images are read from.
+//
+// If cfg is non-nil, it is populated with the "default" and "timeout"
+// settings found at the top level of the file, so callers can select the
+// default entry the same way SelectDefault does for BLS entries.
+func LoadGrubEntries(fsys fs.FS, path string, cfg *GrubConfig) ([]*Entry, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading grub config, %v", err)
+	}
+
+	statements, err := scanGrubStatements(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error scanning grub config, %v", err)
+	}
+
+	root := &grubScope{fsys: fsys}
+
+	var entries []*Entry
+
+	for _, st := range statements {
+		if len(st.words) == 0 {
+			continue
+		}
+
+		switch st.words[0] {
+		case "menuentry", "submenu":
+			found, err := root.parseMenuBlock(st, "")
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, found...)
+		case "set":
+			if cfg != nil {
+				applyGrubSetting(cfg, st.words)
+			}
+
+			root.apply(st.words)
+		default:
+			root.apply(st.words)
+		}
+	}
+
+	return entries, nil
+}
+
+// LoadGrubEntry parses the GRUB configuration file at path and returns its
+// default entry, for backward compatibility with callers that only care
+// about a single entry.
+func LoadGrubEntry(fsys fs.FS, path string) (*Entry, error) {
+	var cfg GrubConfig
+
+	entries, err := LoadGrubEntries(fsys, path, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no menuentry found in grub config")
+	}
+
+	return selectGrubDefault(entries, &cfg), nil
+}
+
+// selectGrubDefault returns the entry referenced by cfg.Default, which may
+// be a zero-based menu index or an entry title, falling back to the first
+// entry when it is empty or does not match.
+func selectGrubDefault(entries []*Entry, cfg *GrubConfig) *Entry {
+	if cfg.Default != "" {
+		if idx, err := strconv.Atoi(cfg.Default); err == nil && idx >= 0 && idx < len(entries) {
+			return entries[idx]
+		}
+
+		for _, e := range entries {
+			if e.Title == cfg.Default {
+				return e
+			}
+		}
+	}
+
+	return entries[0]
+}
+
+// applyGrubSetting records "set default=..." and "set timeout=..."
+// statements into cfg. Other "set" statements are ignored here; they are
+// still applied to the root scope by the caller via grubScope.apply.
+func applyGrubSetting(cfg *GrubConfig, words []string) {
+	if len(words) < 2 {
+		return
+	}
+
+	kv := strings.SplitN(words[1], "=", 2)
+	if len(kv) != 2 {
+		return
+	}
+
+	switch kv[0] {
+	case "default":
+		cfg.Default = unquote(kv[1])
+	case "timeout":
+		cfg.Timeout = unquote(kv[1])
+	}
+}
+
+// grubScope tracks the GRUB interpreter state relevant to resolving an
+// entry's images: the root device, as last set by "search" or "set root=".
+//
+// root is a GRUB device/UUID search criterion (e.g. "hd0,gpt2" or a
+// filesystem UUID), not a path, so it is not usable as a path prefix: fsys
+// is expected to already be rooted at whichever partition GRUB would have
+// resolved root to, the same way LoadEntries is rooted at the ESP. root is
+// therefore tracked for completeness but does not affect loadPath.
+type grubScope struct {
+	fsys fs.FS
+	root string
+}
+
+// clone returns a copy of s, so that settings made inside a submenu or
+// menuentry body do not leak back out to sibling blocks.
+func (s *grubScope) clone() *grubScope {
+	c := *s
+	return &c
+}
+
+// apply interprets a single command line for its effect on scope state:
+// "search" and "set root=" update the root device. "search"'s variable to
+// set defaults to "root" when "--set" is given on its own, and can also be
+// given as a single "--set=root" token; either is followed by the search
+// criterion as the next word.
+func (s *grubScope) apply(words []string) {
+	if len(words) == 0 {
+		return
+	}
+
+	switch words[0] {
+	case "search":
+		for i, w := range words {
+			if i+1 >= len(words) {
+				continue
+			}
+
+			switch {
+			case w == "--set", w == "--set=root":
+				s.root = words[i+1]
+			}
+		}
+	case "set":
+		if len(words) < 2 {
+			return
+		}
+
+		kv := strings.SplitN(words[1], "=", 2)
+		if len(kv) == 2 && kv[0] == "root" {
+			s.root = unquote(kv[1])
+		}
+	}
+}
+
+// parseMenuBlock parses a single menuentry or submenu statement, recursing
+// into submenu bodies and flattening their menuentries into the result with
+// titles of the form "parent > child".
+func (s *grubScope) parseMenuBlock(st grubStatement, parentTitle string) ([]*Entry, error) {
+	if len(st.words) < 2 {
+		return nil, fmt.Errorf("%s missing title", st.words[0])
+	}
+
+	title := unquote(st.words[1])
+	if parentTitle != "" {
+		title = parentTitle + " > " + title
+	}
+
+	body, err := scanGrubStatements(st.body)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %q body, %v", title, err)
+	}
+
+	scope := s.clone()
+
+	if st.words[0] == "submenu" {
+		var entries []*Entry
+
+		for _, child := range body {
+			if len(child.words) == 0 {
+				continue
+			}
+
+			switch child.words[0] {
+			case "menuentry", "submenu":
+				found, err := scope.parseMenuBlock(child, title)
+				if err != nil {
+					return nil, err
+				}
+
+				entries = append(entries, found...)
+			default:
+				scope.apply(child.words)
+			}
+		}
+
+		return entries, nil
+	}
+
+	e := &Entry{fsys: s.fsys, Title: title, file: title}
+
+	for _, child := range body {
+		if err := scope.applyEntryCommand(e, child.words); err != nil {
+			return nil, fmt.Errorf("error parsing %q, %v", title, err)
+		}
+	}
+
+	return []*Entry{e}, nil
+}
+
+// applyEntryCommand interprets a single command line inside a menuentry
+// body, loading kernel, initrd and devicetree images through loadPath.
+func (s *grubScope) applyEntryCommand(e *Entry, words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+
+	switch words[0] {
+	case "search":
+		s.apply(words)
+		return nil
+	case "set":
+		s.apply(words)
+		return nil
+	case "linux", "linuxefi":
+		if len(words) < 2 {
+			return nil
+		}
+
+		data, err := s.loadPath(words[1])
+		if err != nil {
+			return fmt.Errorf("loading linux image %s: %w", words[1], err)
+		}
+
+		e.Linux = data
+
+		if len(words) > 2 {
+			e.Options = strings.Join(words[2:], " ")
+		}
+	case "initrd", "initrdefi":
+		for _, w := range words[1:] {
+			data, err := s.loadPath(w)
+			if err != nil {
+				return fmt.Errorf("loading initrd %s: %w", w, err)
+			}
+
+			e.Initrd = append(e.Initrd, data...)
+		}
+	case "devicetree":
+		if len(words) < 2 {
+			return nil
+		}
+
+		data, err := s.loadPath(words[1])
+		if err != nil {
+			return fmt.Errorf("loading devicetree %s: %w", words[1], err)
+		}
+
+		e.Devicetree = data
+	default:
+		e.ignored += strings.Join(words, " ") + "\n"
+		return nil
+	}
+
+	e.parsed += strings.Join(words, " ") + "\n"
+
+	return nil
+}
+
+// loadPath reads p from the underlying file system. Paths in a menuentry
+// are already relative to the device GRUB's "search"/"set root=" resolved
+// to, which is the same partition fsys is rooted at, so s.root plays no
+// part in resolving p.
+func (s *grubScope) loadPath(p string) ([]byte, error) {
+	p = strings.TrimPrefix(p, "/")
+
+	return fs.ReadFile(s.fsys, p)
+}
@@ -0,0 +1,221 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// scanGrubStatements splits src into a sequence of top-level statements: a
+// plain command line becomes a grubStatement with its words split the same
+// way a POSIX shell would, honoring '...' and "..." quoting and backslash
+// escapes; a command immediately followed by a brace-delimited block (as
+// used by "menuentry ... {" and "submenu ... {") becomes a grubStatement
+// whose body holds the unparsed text between the matching braces. Braces
+// and statement terminators found inside quotes are not treated as
+// structural.
+func scanGrubStatements(src string) ([]grubStatement, error) {
+	var statements []grubStatement
+
+	i, n := 0, len(src)
+
+	for i < n {
+		// Skip whitespace, statement separators and comments between
+		// statements.
+		for i < n && (isGrubSpace(src[i]) || src[i] == ';') {
+			i++
+		}
+
+		if i < n && src[i] == '#' {
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		if i >= n {
+			break
+		}
+
+		words, end, err := scanGrubWords(src, i)
+		if err != nil {
+			return nil, err
+		}
+
+		i = end
+
+		for i < n && isGrubSpace(src[i]) {
+			i++
+		}
+
+		if len(words) == 0 {
+			continue
+		}
+
+		if i < n && src[i] == '{' {
+			body, end, err := scanGrubBlock(src, i)
+			if err != nil {
+				return nil, err
+			}
+
+			i = end
+
+			statements = append(statements, grubStatement{words: words, body: body})
+			continue
+		}
+
+		statements = append(statements, grubStatement{words: words})
+	}
+
+	return statements, nil
+}
+
+func isGrubSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// scanGrubWords tokenizes a single logical line starting at src[i], honoring
+// GRUB's shell-like quoting, and returns the words together with the index
+// of the first byte not consumed, which is either a top-level '{', a
+// statement terminator, or the end of the string.
+func scanGrubWords(src string, i int) ([]string, int, error) {
+	var words []string
+
+	n := len(src)
+
+	for i < n {
+		for i < n && (src[i] == ' ' || src[i] == '\t') {
+			i++
+		}
+
+		if i >= n || src[i] == '\n' || src[i] == ';' || src[i] == '{' || src[i] == '#' {
+			break
+		}
+
+		var word strings.Builder
+
+		for i < n && !isGrubSpace(src[i]) && src[i] != ';' && src[i] != '{' {
+			switch src[i] {
+			case '\'':
+				end := strings.IndexByte(src[i+1:], '\'')
+				if end < 0 {
+					return nil, 0, fmt.Errorf("unterminated single quote")
+				}
+
+				word.WriteString(src[i+1 : i+1+end])
+				i += end + 2
+			case '"':
+				j := i + 1
+				for j < n && src[j] != '"' {
+					if src[j] == '\\' && j+1 < n {
+						j++
+					}
+					j++
+				}
+
+				if j >= n {
+					return nil, 0, fmt.Errorf("unterminated double quote")
+				}
+
+				word.WriteString(unescape(src[i+1 : j]))
+				i = j + 1
+			case '\\':
+				if i+1 < n {
+					word.WriteByte(src[i+1])
+					i += 2
+				} else {
+					i++
+				}
+			default:
+				word.WriteByte(src[i])
+				i++
+			}
+		}
+
+		words = append(words, word.String())
+	}
+
+	return words, i, nil
+}
+
+// scanGrubBlock extracts the text between the braces starting at src[i],
+// which must be '{', returning the body and the index just past the
+// matching '}'. Quoted braces are not treated as structural.
+func scanGrubBlock(src string, i int) (string, int, error) {
+	n := len(src)
+
+	if i >= n || src[i] != '{' {
+		return "", 0, fmt.Errorf("expected '{'")
+	}
+
+	start := i + 1
+	depth := 1
+	i++
+
+	for i < n && depth > 0 {
+		switch src[i] {
+		case '\'':
+			end := strings.IndexByte(src[i+1:], '\'')
+			if end < 0 {
+				return "", 0, fmt.Errorf("unterminated single quote")
+			}
+			i += end + 2
+			continue
+		case '"':
+			j := i + 1
+			for j < n && src[j] != '"' {
+				if src[j] == '\\' && j+1 < n {
+					j++
+				}
+				j++
+			}
+			if j >= n {
+				return "", 0, fmt.Errorf("unterminated double quote")
+			}
+			i = j + 1
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+
+		i++
+	}
+
+	if depth != 0 {
+		return "", 0, fmt.Errorf("unmatched '{'")
+	}
+
+	return src[start : i-1], i, nil
+}
+
+// unescape interprets backslash escapes inside a double-quoted string.
+func unescape(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// unquote strips a single layer of matching '...' or "..." quoting from s,
+// as used in "set key=value" assignments.
+func unquote(s string) string {
+	if len(s) >= 2 {
+		if (s[0] == '\'' && s[len(s)-1] == '\'') || (s[0] == '"' && s[len(s)-1] == '"') {
+			return s[1 : len(s)-1]
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,152 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestScanGrubWords(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"plain", "linux /vmlinuz root=/dev/sda1 ro", []string{"linux", "/vmlinuz", "root=/dev/sda1", "ro"}},
+		{"single-quoted", `menuentry 'GNU/Linux {with braces}'`, []string{"menuentry", "GNU/Linux {with braces}"}},
+		{"double-quoted-escape", `linux "/vmlinuz --opt=\"value\""`, []string{"linux", `/vmlinuz --opt="value"`}},
+		{"backslash-escape", `linux /vmlinuz\ with\ spaces`, []string{"linux", "/vmlinuz with spaces"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, _, err := scanGrubWords(c.src, 0)
+			if err != nil {
+				t.Fatalf("scanGrubWords(%q): %v", c.src, err)
+			}
+
+			if len(got) != len(c.want) {
+				t.Fatalf("scanGrubWords(%q) = %v, want %v", c.src, got, c.want)
+			}
+
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("scanGrubWords(%q)[%d] = %q, want %q", c.src, i, got[i], c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScanGrubStatementsQuotedBrace(t *testing.T) {
+	src := `menuentry 'Quoted "{" title' {
+	linux /vmlinuz
+}
+`
+
+	statements, err := scanGrubStatements(src)
+	if err != nil {
+		t.Fatalf("scanGrubStatements: %v", err)
+	}
+
+	if len(statements) != 1 {
+		t.Fatalf("got %d statements, want 1", len(statements))
+	}
+
+	st := statements[0]
+
+	if st.words[0] != "menuentry" {
+		t.Fatalf("words[0] = %q, want menuentry", st.words[0])
+	}
+
+	if st.words[1] != `Quoted "{" title` {
+		t.Fatalf("title = %q, want to include the literal brace", st.words[1])
+	}
+
+	body, err := scanGrubStatements(st.body)
+	if err != nil {
+		t.Fatalf("scanning body: %v", err)
+	}
+
+	if len(body) != 1 || body[0].words[0] != "linux" {
+		t.Fatalf("body statements = %v, want a single linux command", body)
+	}
+}
+
+func TestLoadGrubEntriesSubmenu(t *testing.T) {
+	fsys := fstest.MapFS{
+		"6.1.0-300.fc38.x86_64/vmlinuz": &fstest.MapFile{Data: []byte("kernel-a")},
+		"6.0.0-300.fc38.x86_64/vmlinuz": &fstest.MapFile{Data: []byte("kernel-b")},
+	}
+
+	cfg := `set default=0
+set timeout=5
+
+menuentry 'Top Level' {
+	linux /6.1.0-300.fc38.x86_64/vmlinuz root=/dev/sda1
+}
+
+submenu 'Advanced options' {
+	menuentry 'Recovery mode' {
+		linux /6.0.0-300.fc38.x86_64/vmlinuz single
+	}
+}
+`
+
+	fsys["grub.cfg"] = &fstest.MapFile{Data: []byte(cfg)}
+
+	var gcfg GrubConfig
+
+	entries, err := LoadGrubEntries(fsys, "grub.cfg", &gcfg)
+	if err != nil {
+		t.Fatalf("LoadGrubEntries: %v", err)
+	}
+
+	if gcfg.Default != "0" || gcfg.Timeout != "5" {
+		t.Fatalf("GrubConfig = %+v, want Default=0 Timeout=5", gcfg)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(entries), entries)
+	}
+
+	if entries[0].Title != "Top Level" {
+		t.Errorf("entries[0].Title = %q, want %q", entries[0].Title, "Top Level")
+	}
+
+	if string(entries[0].Linux) != "kernel-a" {
+		t.Errorf("entries[0].Linux = %q, want kernel-a", entries[0].Linux)
+	}
+
+	wantSubmenuTitle := "Advanced options > Recovery mode"
+	if entries[1].Title != wantSubmenuTitle {
+		t.Errorf("entries[1].Title = %q, want %q", entries[1].Title, wantSubmenuTitle)
+	}
+
+	if string(entries[1].Linux) != "kernel-b" {
+		t.Errorf("entries[1].Linux = %q, want kernel-b", entries[1].Linux)
+	}
+
+	e, err := LoadGrubEntry(fsys, "grub.cfg")
+	if err != nil {
+		t.Fatalf("LoadGrubEntry: %v", err)
+	}
+
+	if e.Title != "Top Level" {
+		t.Errorf("LoadGrubEntry default title = %q, want %q", e.Title, "Top Level")
+	}
+}
+
+func TestGrubScopeApplySearchSetRoot(t *testing.T) {
+	scope := &grubScope{}
+
+	scope.apply([]string{"search", "--no-floppy", "--fs-uuid", "--set=root", "2efd3a3c-aaaa"})
+
+	if scope.root != "2efd3a3c-aaaa" {
+		t.Errorf("scope.root = %q, want the resolved search criterion", scope.root)
+	}
+}
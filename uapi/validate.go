@@ -0,0 +1,69 @@
+// Copyright (c) The go-boot authors. All Rights Reserved.
+//
+// Use of this source code is governed by the license
+// that can be found in the LICENSE file.
+
+package uapi
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Validate checks that the entry looks bootable: that a kernel image is
+// present and recognizable as a bzImage, PE (EFI stub or UKI) or ARM64
+// Image, before a caller attempts to hand it to KexecLoad or Boot. Options
+// is not required: a kernel may rely on a built-in command line, and UKIs
+// commonly have no .cmdline section at all.
+func (e *Entry) Validate() error {
+	if len(e.Linux) == 0 {
+		return fmt.Errorf("entry validation: missing kernel image")
+	}
+
+	if !isRecognizedKernelImage(e.Linux) {
+		return fmt.Errorf("entry validation: unrecognized kernel image format")
+	}
+
+	return nil
+}
+
+// isRecognizedKernelImage sniffs data's magic numbers to tell whether it
+// looks like a Linux bzImage, a PE image (EFI stub kernel or UKI), or an
+// ARM64 Image.
+func isRecognizedKernelImage(data []byte) bool {
+	return isBzImage(data) || isPEImage(data) || isARM64Image(data)
+}
+
+// bzImage header fields, see Documentation/arch/x86/boot.rst: the 4-byte
+// magic "HdrS" sits at offset 0x202 in the real-mode kernel header.
+const bzImageMagicOffset = 0x202
+
+func isBzImage(data []byte) bool {
+	return len(data) >= bzImageMagicOffset+4 &&
+		string(data[bzImageMagicOffset:bzImageMagicOffset+4]) == "HdrS"
+}
+
+// PE files start with the "MZ" DOS stub magic; the offset of the real PE
+// header is a little-endian uint32 at 0x3c, and that header starts with
+// "PE\x00\x00".
+func isPEImage(data []byte) bool {
+	if len(data) < 0x40 || data[0] != 'M' || data[1] != 'Z' {
+		return false
+	}
+
+	peOffset := binary.LittleEndian.Uint32(data[0x3c:0x40])
+	if peOffset > uint32(len(data))-4 {
+		return false
+	}
+
+	return string(data[peOffset:peOffset+4]) == "PE\x00\x00"
+}
+
+// ARM64 Image header fields, see Documentation/arch/arm64/booting.rst: the
+// 4-byte magic "ARM\x64" sits at offset 0x38.
+const arm64ImageMagicOffset = 0x38
+
+func isARM64Image(data []byte) bool {
+	return len(data) >= arm64ImageMagicOffset+4 &&
+		string(data[arm64ImageMagicOffset:arm64ImageMagicOffset+4]) == "ARM\x64"
+}